@@ -0,0 +1,169 @@
+// Package config loads the bar's color scheme and per-module thresholds
+// from a user-editable JSON file, feeds the scheme into barista.run/colors,
+// and exposes the thresholds for use by output callbacks. LoadFromXDG
+// installs a SIGHUP handler that reloads the file and notifies Subscribe
+// channels, so the bar can be re-themed without a restart.
+package config // import "github.com/trbjo/barista_trbjo/config"
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"barista.run/base/value"
+	"barista.run/colors"
+	l "barista.run/logging"
+
+	"github.com/spf13/afero"
+)
+
+// Scheme holds the bar's named colors, as hex strings (e.g. "#35699a"),
+// applied via colors.Set under the same names.
+type Scheme struct {
+	Accent string `json:"accent"`
+	Bad    string `json:"bad"`
+	Good   string `json:"good"`
+	Warn   string `json:"warn"`
+}
+
+// Thresholds holds the ascending cutoffs used by output callbacks to pick
+// an icon or color tier. For example, BatteryPct of [15, 35, 65, 85]
+// produces 5 tiers (empty, quarter, half, three_quarters, full); see
+// Bucket for how a value maps to a tier index.
+type Thresholds struct {
+	BatteryPct []float64 `json:"battery_pct"`
+	VolumePct  []float64 `json:"volume_pct"`
+	MemoryGB   []float64 `json:"memory_gb"`
+	TempC      []float64 `json:"temp_c"`
+}
+
+// Config is the bar's loaded configuration.
+type Config struct {
+	Colors     Scheme     `json:"colors"`
+	Thresholds Thresholds `json:"thresholds"`
+}
+
+// Default holds the values used for anything not set in the loaded file,
+// matching the bar's previous compiled-in constants.
+var Default = Config{
+	Colors: Scheme{
+		Accent: "#3584e4",
+		Bad:    "#ff0000",
+		Good:   "#00ff00",
+		Warn:   "#ffff00",
+	},
+	Thresholds: Thresholds{
+		BatteryPct: []float64{15, 35, 65, 85},
+		VolumePct:  []float64{34, 67},
+		MemoryGB:   []float64{0.5, 2},
+		TempC:      []float64{80, 90},
+	},
+}
+
+var fs = afero.NewOsFs()
+var current value.Value // of *Config
+
+func init() {
+	current.Set(&Default)
+	l.Attach(nil, &current, "config.current")
+}
+
+// Get returns the currently active configuration.
+func Get() *Config {
+	return current.Get().(*Config)
+}
+
+// Subscribe returns a channel that's notified whenever the configuration
+// is reloaded (see LoadFromXDG), and a function to stop listening.
+func Subscribe() (<-chan struct{}, func()) {
+	return current.Subscribe()
+}
+
+// Bucket returns the number of cutoffs that v is greater than or equal to,
+// i.e. the index of the tier v falls into for an ascending list of cutoffs.
+func Bucket(v float64, cutoffs []float64) int {
+	i := 0
+	for i < len(cutoffs) && v >= cutoffs[i] {
+		i++
+	}
+	return i
+}
+
+func configDir() string {
+	configRoot := os.ExpandEnv("$HOME/.config")
+	if xdgConfig, ok := os.LookupEnv("XDG_CONFIG_HOME"); ok {
+		configRoot = xdgConfig
+	}
+	return configRoot
+}
+
+// LoadFromXDG loads the configuration from relPath under the XDG config
+// directory (e.g. "barista/config.json"), applies its color scheme, and
+// makes the result available via Get. A missing file is not an error; the
+// bar simply runs with Default. It also installs a SIGHUP handler that
+// reloads the file and notifies Subscribe, so callers can re-run their
+// output funcs to pick up the new scheme/thresholds.
+func LoadFromXDG(relPath string) error {
+	path := filepath.Join(configDir(), relPath)
+	if err := load(path); err != nil {
+		return err
+	}
+
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	go func() {
+		for range sighup {
+			if err := load(path); err != nil {
+				fmt.Fprintf(os.Stderr, "config: reload of %s failed: %v\n", path, err)
+			}
+		}
+	}()
+	return nil
+}
+
+func load(path string) error {
+	// cfg starts as a copy of Default, but Default's threshold slices must be
+	// cloned rather than shared: json.Decode below writes into them in place
+	// when the decoded array fits their existing capacity, which would
+	// otherwise corrupt Default for the rest of the process.
+	cfg := Default
+	cfg.Thresholds = Thresholds{
+		BatteryPct: append([]float64(nil), Default.Thresholds.BatteryPct...),
+		VolumePct:  append([]float64(nil), Default.Thresholds.VolumePct...),
+		MemoryGB:   append([]float64(nil), Default.Thresholds.MemoryGB...),
+		TempC:      append([]float64(nil), Default.Thresholds.TempC...),
+	}
+	f, err := fs.Open(path)
+	switch {
+	case err == nil:
+		defer f.Close()
+		if err := json.NewDecoder(f).Decode(&cfg); err != nil {
+			return err
+		}
+	case os.IsNotExist(err):
+		// No config file yet; run with Default.
+	default:
+		return err
+	}
+	applyColors(cfg.Colors)
+	current.Set(&cfg)
+	return nil
+}
+
+func applyColors(s Scheme) {
+	set := func(name, hex string) {
+		if hex == "" {
+			return
+		}
+		if c := colors.Hex(hex); c != nil {
+			colors.Set(name, c)
+		}
+	}
+	set("accent", s.Accent)
+	set("bad", s.Bad)
+	set("good", s.Good)
+	set("warn", s.Warn)
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDoesNotCorruptDefault(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	originalBatteryPct := append([]float64(nil), Default.Thresholds.BatteryPct...)
+
+	require.NoError(t, afero.WriteFile(fs, "with-thresholds.json",
+		[]byte(`{"thresholds":{"battery_pct":[10,40,70,90]}}`), 0644))
+	require.NoError(t, load("with-thresholds.json"))
+	require.Equal(t, []float64{10, 40, 70, 90}, Get().Thresholds.BatteryPct)
+	require.Equal(t, originalBatteryPct, Default.Thresholds.BatteryPct,
+		"loading a config with thresholds must not mutate Default")
+
+	require.NoError(t, afero.WriteFile(fs, "no-thresholds.json", []byte(`{}`), 0644))
+	require.NoError(t, load("no-thresholds.json"))
+	require.Equal(t, originalBatteryPct, Get().Thresholds.BatteryPct,
+		"a later load with no thresholds must fall back to the untouched Default")
+}
@@ -0,0 +1,78 @@
+/*
+Package nerdfont provides support for Nerd Fonts
+(https://www.nerdfonts.com/), a collection of icon fonts patched with
+glyphs from FontAwesome, Material Design Icons, and others.
+
+It requires a copy of the Nerd Fonts CSS (as published alongside the
+patched fonts, e.g. `css/nerd-fonts-generated.css` in the Nerd Fonts
+repo) and the corresponding font installed as a system font.
+
+Example usage:
+
+	nerdfont.Load("nerd-fonts.css")
+	...
+	return nerdfont.Icon("nf-fa-battery_three_quarters").Color(colors.Hex("#ddd"))
+*/
+package nerdfont // import "github.com/trbjo/barista_trbjo/icons/nerdfont"
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"barista.run/pango"
+	"barista.run/pango/icons"
+
+	"github.com/spf13/afero"
+)
+
+var fs = afero.NewOsFs()
+
+// ruleRe matches a single Nerd Font icon rule, e.g.
+// `.nf-fa-battery_quarter:before { content: "\f243"; }`. The captured name
+// excludes the "nf-" prefix, since pango.Icon("nf-fa-...") strips it before
+// looking the icon up in its provider (see pango.AddIconProvider).
+var ruleRe = regexp.MustCompile(`^\.nf-([a-zA-Z0-9_-]+):before\s*\{\s*content:\s*"\\([0-9a-fA-F]+)";?\s*\}\s*$`)
+
+// Load parses the given Nerd Font CSS file and registers its icons with
+// pango under the "nf" provider, so that e.g. "nf-fa-battery_quarter"
+// resolves to the corresponding glyph.
+func Load(cssPath string) error {
+	f, err := fs.Open(cssPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	nf := icons.NewProvider("nf")
+	nf.Font("Symbols Nerd Font")
+
+	found := 0
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		match := ruleRe.FindStringSubmatch(line)
+		if match == nil {
+			// Not an icon rule (could be a font-face block, comment, etc).
+			continue
+		}
+		if err := nf.Hex(match[1], match[2]); err != nil {
+			return fmt.Errorf("nerdfont: invalid codepoint for %s: %w", match[1], err)
+		}
+		found++
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	if found == 0 {
+		return fmt.Errorf("nerdfont: no icons found in %s", cssPath)
+	}
+	return nil
+}
+
+// Icon returns a pango node that displays the named Nerd Font glyph, e.g.
+// "nf-fa-battery_quarter". It is a thin wrapper around pango.Icon.
+func Icon(ident string) *pango.Node {
+	return pango.Icon(ident)
+}
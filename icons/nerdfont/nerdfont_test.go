@@ -0,0 +1,50 @@
+package nerdfont
+
+import (
+	"testing"
+
+	"barista.run/testing/pango"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+const testCSS = `
+/* Font Awesome */
+.nf-fa-battery_quarter:before { content: "\f243"; }
+.nf-fa-battery_three_quarters:before { content: "\f241"; }
+.nf-fa-volume_off:before { content: "\f026"; }
+.nf-fa-wifi:before { content: "\f1eb"; }
+.nf-fa-bolt:before { content: "\f0e7"; }
+
+/* Not an icon rule; must be skipped without matching. */
+@font-face {
+  font-family: "Symbols Nerd Font";
+  src: url("nerd-fonts-symbols.woff2") format("woff2");
+}
+`
+
+func TestLoadMultiSegmentNames(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "nerd-fonts.css", []byte(testCSS), 0644))
+
+	require.NoError(t, Load("nerd-fonts.css"))
+
+	for _, ident := range []string{
+		"nf-fa-battery_quarter",
+		"nf-fa-battery_three_quarters",
+		"nf-fa-volume_off",
+		"nf-fa-wifi",
+		"nf-fa-bolt",
+	} {
+		require.NotEmpty(t, Icon(ident).String(), "icon %s did not register", ident)
+	}
+}
+
+func TestLoadNoIconsFound(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "empty.css", []byte("/* nothing here */"), 0644))
+
+	require.Error(t, Load("empty.css"))
+	pango.AssertText(t, "", Icon("nf-fa-wifi").String(), "no icons were registered")
+}
@@ -34,7 +34,7 @@ import (
 	"barista.run/format"
 	"barista.run/modules/battery"
 	"barista.run/modules/clock"
-	"barista.run/modules/github"
+	"barista.run/modules/media"
 	"barista.run/modules/meminfo"
 	"barista.run/modules/netinfo"
 	"barista.run/modules/netspeed"
@@ -45,22 +45,21 @@ import (
 	"barista.run/outputs"
 	"barista.run/pango"
 
+	"github.com/trbjo/barista_trbjo/config"
+	"github.com/trbjo/barista_trbjo/icons/nerdfont"
+	"github.com/trbjo/barista_trbjo/modules/github"
+	"github.com/trbjo/barista_trbjo/modules/mprisvolume"
+	"github.com/trbjo/barista_trbjo/modules/system"
+	"github.com/trbjo/barista_trbjo/oauth/device"
 	keyring "github.com/zalando/go-keyring"
 )
 
-type MyColor struct {
-	R, G, B, A uint32
-}
-
-func (c MyColor) RGBA() (uint32, uint32, uint32, uint32) {
-	return c.R, c.G, c.B, c.A
-}
-
-var (
-	Accent  = MyColor{13621, 33924, 58596, 65535}
-	MyRed   = MyColor{0xffff, 0, 0, 0xffff}
-	MyGreen = MyColor{0, 0xffff, 0, 0xffff}
-	MyBlue  = MyColor{0, 0, 0xffff, 0xffff}
+// Preferred sensor for the system module's CPU temperature, matching
+// /sys/class/hwmon/*/name and *_label. Falls back to the "x86_pkg_temp"
+// thermal zone if this chip/label isn't found.
+const (
+	hwmonChip  = "k10temp"
+	hwmonLabel = "Tctl"
 )
 
 var spacer = pango.Text(" ").XXSmall()
@@ -119,6 +118,7 @@ func setupOauthEncryption() error {
 		keyring.Set(service, username, secret)
 	}
 	oauth.SetEncryptionKey(secretBytes)
+	device.SetEncryptionKey(secretBytes)
 	return nil
 }
 
@@ -126,6 +126,26 @@ func main() {
 	if err := setupOauthEncryption(); err != nil {
 		panic(fmt.Sprintf("Could not setup oauth token encryption: %v", err))
 	}
+	if err := nerdfont.Load("nerd-fonts.css"); err != nil {
+		panic(fmt.Sprintf("Could not load nerd font icons: %v", err))
+	}
+	if err := config.LoadFromXDG("barista/config.json"); err != nil {
+		panic(fmt.Sprintf("Could not load config: %v", err))
+	}
+
+	// refreshers re-run the Output func of every module whose rendering
+	// depends on config.Get(), so that a SIGHUP-triggered reload (see
+	// config.LoadFromXDG) is reflected immediately instead of waiting for
+	// the next unrelated data change.
+	var refreshers []func()
+	reload, _ := config.Subscribe()
+	go func() {
+		for range reload {
+			for _, refresh := range refreshers {
+				refresh()
+			}
+		}
+	}()
 
 	localtime := clock.Local().
 		Output(time.Second, func(now time.Time) bar.Output {
@@ -134,63 +154,95 @@ func main() {
 			)
 		})
 
-	batt := battery.All().Output(
-		func(i battery.Info) bar.Output {
-			if i.Status == battery.Disconnected || i.Status == battery.Unknown {
-				return nil
-			}
-			iconName := ""
-			pct := i.RemainingPct()
+	batteryIcons := []string{
+		"nf-fa-battery_empty",
+		"nf-fa-battery_quarter",
+		"nf-fa-battery_half",
+		"nf-fa-battery_three_quarters",
+		"nf-fa-battery_full",
+	}
+	battOutput := func(i battery.Info) bar.Output {
+		if i.Status == battery.Disconnected || i.Status == battery.Unknown {
+			return nil
+		}
+		pct := i.RemainingPct()
 
-			if i.Status == battery.Charging {
-				iconName = ""
-				return outputs.Textf("%s %2d%%", iconName, pct)
-			}
-			switch {
-			case pct < 15:
-				iconName = ""
-			case pct < 35:
-				iconName = ""
-			case pct < 65:
-				iconName = ""
-			case pct < 85:
-				iconName = ""
-			case pct < 50:
-				iconName = ""
-			default:
-				iconName = ""
-			}
-			return outputs.Textf("%s %2d%%", iconName, pct)
-		})
+		if i.Status == battery.Charging {
+			return outputs.Pango(nerdfont.Icon("nf-fa-bolt"), fmt.Sprintf(" %2d%%", pct))
+		}
+		tier := config.Bucket(float64(pct), config.Get().Thresholds.BatteryPct)
+		return outputs.Pango(nerdfont.Icon(batteryIcons[tier]), fmt.Sprintf(" %2d%%", pct))
+	}
+	batt := battery.All().Output(battOutput)
+	refreshers = append(refreshers, func() { batt.Output(battOutput) })
 
-	vol := volume.New(alsa.DefaultMixer()).Output(func(v volume.Volume) bar.Output {
+	volumeIcons := []string{"nf-fa-volume_off", "nf-fa-volume_down", "nf-fa-volume_up"}
+	volOutput := func(v volume.Volume) bar.Output {
 		if v.Mute {
-			return outputs.Textf("")
+			return outputs.Pango(nerdfont.Icon("nf-fa-volume_off"))
 		}
-		iconName := ""
 		pct := v.Pct()
-		if pct > 66 {
-			iconName = ""
-		} else if pct > 33 {
-			iconName = ""
+		tier := config.Bucket(float64(pct), config.Get().Thresholds.VolumePct)
+		return outputs.Pango(nerdfont.Icon(volumeIcons[tier]), fmt.Sprintf(" %2d%%", pct))
+	}
+	vol := volume.New(alsa.DefaultMixer()).Output(volOutput)
+	refreshers = append(refreshers, func() { vol.Output(volOutput) })
+
+	player := media.Auto().Output(func(i media.Info) bar.Output {
+		if !i.Connected() {
+			return nil
+		}
+		title := truncate(i.Title, 30)
+		if i.Artist != "" {
+			title = fmt.Sprintf("%s - %s", truncate(i.Artist, 20), title)
+		}
+		icon := "nf-fa-play"
+		if i.Playing() {
+			icon = "nf-fa-pause"
 		}
-		return outputs.Textf("%s %2d%%", iconName, pct)
+		return outputs.Pango(nerdfont.Icon(icon), " "+title).OnClick(func(e bar.Event) {
+			switch e.Button {
+			case bar.ButtonLeft:
+				i.PlayPause()
+			case bar.ButtonMiddle:
+				i.Next()
+			case bar.ScrollUp:
+				mprisvolume.Adjust(i.PlayerName, 0.05)
+			case bar.ScrollDown:
+				mprisvolume.Adjust(i.PlayerName, -0.05)
+			}
+		})
 	})
 
-	freeMem := meminfo.New().Output(func(m meminfo.Info) bar.Output {
+	freeMemOutput := func(m meminfo.Info) bar.Output {
 		out := outputs.Textf(format.IBytesize(m.Available()))
-
-		freeGigs := m.Available().Gigabytes()
-		switch {
-		case freeGigs < 0.5:
+		switch config.Bucket(m.Available().Gigabytes(), config.Get().Thresholds.MemoryGB) {
+		case 0:
 			out.Urgent(true)
-		case freeGigs < 1:
-			out.Color(MyRed)
-		case freeGigs < 2:
-			out.Color(MyRed)
+		case 1:
+			out.Color(colors.Scheme("bad"))
 		}
 		return out
-	})
+	}
+	freeMem := meminfo.New().Output(freeMemOutput)
+	refreshers = append(refreshers, func() { freeMem.Output(freeMemOutput) })
+
+	sysOutput := func(i system.Info) bar.Output {
+		out := outputs.Textf("up: %s, load: %0.2f", format.Duration(i.Uptime), i.Loads[0])
+		if i.TempAvailable {
+			out = outputs.Textf("up: %s, load: %0.2f, %.0f℃",
+				format.Duration(i.Uptime), i.Loads[0], i.Temp.Celsius())
+			switch config.Bucket(i.Temp.Celsius(), config.Get().Thresholds.TempC) {
+			case 1:
+				out.Color(colors.Scheme("bad"))
+			case 2:
+				out.Urgent(true)
+			}
+		}
+		return out
+	}
+	sys := system.New(hwmonChip, hwmonLabel).Output(sysOutput)
+	refreshers = append(refreshers, func() { sys.Output(sysOutput) })
 
 	sub := netlink.Any()
 	iface := sub.Get().Name
@@ -202,10 +254,10 @@ func main() {
 		})
 
 	showNetInfo := make(chan bool, 1)
-	wlan := wlan.Any().Output(func(i wlan.Info) bar.Output {
+	wlanOutput := func(i wlan.Info) bar.Output {
 		if i.Connected() {
 			showNetInfo <- false
-			icon := outputs.Text(" ").Color(Accent)
+			icon := outputs.Pango(nerdfont.Icon("nf-fa-wifi"), " ").Color(colors.Scheme("accent"))
 			out := outputs.Group(icon)
 			ssid := outputs.Textf(i.SSID)
 			out.Append((ssid))
@@ -214,9 +266,11 @@ func main() {
 		showNetInfo <- true
 		return nil
 
-	})
+	}
+	wlan := wlan.Any().Output(wlanOutput)
+	refreshers = append(refreshers, func() { wlan.Output(wlanOutput) })
 
-	netInfo := netinfo.New().Output(func(s netinfo.State) bar.Output {
+	netInfoOutput := func(s netinfo.State) bar.Output {
 		shouldShow := <-showNetInfo
 		if !shouldShow {
 			return nil
@@ -225,9 +279,11 @@ func main() {
 			return outputs.Text("No network").Color(colors.Scheme("bad"))
 		}
 		return outputs.Textf("%s: %v", s.Name, s.IPs[0])
-	})
+	}
+	netInfo := netinfo.New().Output(netInfoOutput)
+	refreshers = append(refreshers, func() { netInfo.Output(netInfoOutput) })
 
-	ghNotify := github.New(os.Getenv("GITHUB_CLIENT_ID"), os.Getenv("GITHUB_CLIENT_SECRET")).
+	ghNotify := github.New(os.Getenv("GITHUB_CLIENT_ID")).
 		Output(func(n github.Notifications) bar.Output {
 			if n.Total() == 0 {
 				return nil
@@ -256,7 +312,9 @@ func main() {
 		netInfo,
 		wlan,
 		vol,
+		player,
 		freeMem,
+		sys,
 		ghNotify,
 		batt,
 		localtime,
@@ -0,0 +1,43 @@
+// Package mprisvolume adjusts the playback volume of an MPRIS-compatible
+// media player over D-Bus. It exists alongside barista.run/modules/media
+// because that package's Info neither tracks nor exposes Volume -- its
+// D-Bus subscription only watches Rate, Shuffle, PlaybackStatus, and
+// Metadata, and there's no hook to add another property to it.
+package mprisvolume // import "github.com/trbjo/barista_trbjo/modules/mprisvolume"
+
+import (
+	"fmt"
+
+	godbus "github.com/godbus/dbus/v5"
+)
+
+const playerIface = "org.mpris.MediaPlayer2.Player"
+
+// Adjust changes the volume of the named MPRIS player (media.Info.PlayerName)
+// by delta, a fraction of full volume in [-1, 1], clamping the result to
+// [0, 1].
+func Adjust(playerName string, delta float64) error {
+	conn, err := godbus.SessionBus()
+	if err != nil {
+		return err
+	}
+	obj := conn.Object(
+		fmt.Sprintf("org.mpris.MediaPlayer2.%s", playerName),
+		"/org/mpris/MediaPlayer2")
+
+	var current godbus.Variant
+	if err := obj.Call("org.freedesktop.DBus.Properties.Get", 0,
+		playerIface, "Volume").Store(&current); err != nil {
+		return err
+	}
+	vol, _ := current.Value().(float64)
+	vol += delta
+	switch {
+	case vol < 0:
+		vol = 0
+	case vol > 1:
+		vol = 1
+	}
+	return obj.Call("org.freedesktop.DBus.Properties.Set", 0,
+		playerIface, "Volume", godbus.MakeVariant(vol)).Err
+}
@@ -0,0 +1,195 @@
+// Package system implements an i3bar module that shows system load average,
+// uptime, and CPU temperature, in the spirit of barista.run/modules/sysinfo
+// and barista.run/modules/meminfo. CPU temperature reading is folded in here
+// (rather than composed from barista.run/modules/cputemp or
+// barista.run/modules/hwmon) because choosing between a preferred hwmon
+// sensor and the default thermal zone needs to happen once, synchronously,
+// before the module starts streaming -- the upstream modules can only be
+// picked from, not probed, ahead of time.
+package system // import "github.com/trbjo/barista_trbjo/modules/system"
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"barista.run/bar"
+	"barista.run/base/value"
+	l "barista.run/logging"
+	"barista.run/outputs"
+	"barista.run/timing"
+
+	"github.com/martinlindhe/unit"
+	"github.com/spf13/afero"
+	"golang.org/x/sys/unix"
+)
+
+// Info wraps the system load, uptime, and (if available) CPU temperature.
+type Info struct {
+	Uptime time.Duration
+	Loads  [3]float64
+
+	// Temp is the CPU temperature, and TempAvailable reports whether a
+	// usable sensor was found. Temp is zero when TempAvailable is false.
+	Temp          unit.Temperature
+	TempAvailable bool
+}
+
+// Module represents a bar.Module that displays system load, uptime, and
+// CPU temperature.
+type Module struct {
+	thermalFile string
+	scheduler   *timing.Scheduler
+	outputFunc  value.Value // of func(Info) bar.Output
+}
+
+func defaultOutput(i Info) bar.Output {
+	if i.TempAvailable {
+		return outputs.Textf("up: %s, load: %0.2f, %.1f℃", i.Uptime, i.Loads[0], i.Temp.Celsius())
+	}
+	return outputs.Textf("up: %s, load: %0.2f", i.Uptime, i.Loads[0])
+}
+
+// New creates a system module. It prefers the hwmon sensor identified by
+// name and label (e.g. New("k10temp", "Tctl"), matching
+// /sys/class/hwmon/*/name and *_label), falling back to the default
+// "x86_pkg_temp" thermal zone, and to no temperature reading at all if
+// neither is found.
+func New(hwmonName, hwmonLabel string) *Module {
+	m := &Module{
+		thermalFile: findThermalFile(hwmonName, hwmonLabel),
+		scheduler:   timing.NewScheduler(),
+	}
+	l.Register(m, "scheduler", "outputFunc")
+	m.RefreshInterval(3 * time.Second)
+	m.Output(defaultOutput)
+	return m
+}
+
+// RefreshInterval configures the polling frequency.
+func (m *Module) RefreshInterval(interval time.Duration) *Module {
+	m.scheduler.Every(interval)
+	return m
+}
+
+// Output configures a module to display the output of a user-defined function.
+func (m *Module) Output(outputFunc func(Info) bar.Output) *Module {
+	m.outputFunc.Set(outputFunc)
+	return m
+}
+
+// Stream starts the module.
+func (m *Module) Stream(s bar.Sink) {
+	info, err := m.get()
+	outputFunc := m.outputFunc.Get().(func(Info) bar.Output)
+	nextOutputFunc, done := m.outputFunc.Subscribe()
+	defer done()
+	for {
+		if s.Error(err) {
+			return
+		}
+		s.Output(outputFunc(info))
+		select {
+		case <-m.scheduler.C:
+			info, err = m.get()
+		case <-nextOutputFunc:
+			outputFunc = m.outputFunc.Get().(func(Info) bar.Output)
+		}
+	}
+}
+
+const loadScale = 65536.0 // LINUX_SYSINFO_LOADS_SCALE
+
+func (m *Module) get() (Info, error) {
+	var sysinfoT unix.Sysinfo_t
+	if err := sysinfo(&sysinfoT); err != nil {
+		return Info{}, err
+	}
+	info := Info{
+		Uptime: time.Duration(sysinfoT.Uptime) * time.Second,
+		Loads: [3]float64{
+			float64(sysinfoT.Loads[0]) / loadScale,
+			float64(sysinfoT.Loads[1]) / loadScale,
+			float64(sysinfoT.Loads[2]) / loadScale,
+		},
+	}
+	if m.thermalFile != "" {
+		if temp, err := readTemp(m.thermalFile); err == nil {
+			info.Temp = temp
+			info.TempAvailable = true
+		}
+	}
+	return info, nil
+}
+
+// To allow tests to mock out unix.Sysinfo.
+var sysinfo = unix.Sysinfo
+
+var fs = afero.NewOsFs()
+
+func readTemp(thermalFile string) (unit.Temperature, error) {
+	bytes, err := afero.ReadFile(fs, thermalFile)
+	if err != nil {
+		return 0, err
+	}
+	milliC, err := strconv.Atoi(strings.TrimSpace(string(bytes)))
+	if err != nil {
+		return 0, err
+	}
+	return unit.FromCelsius(float64(milliC) / 1000.0), nil
+}
+
+// findThermalFile locates the /sys file to read the CPU temperature from,
+// preferring the hwmon sensor matching name/label and falling back to the
+// "x86_pkg_temp" thermal zone. It returns "" if neither is found.
+func findThermalFile(name, label string) string {
+	if f := hwmonFile(name, label); f != "" {
+		return f
+	}
+	return thermalZoneOfType("x86_pkg_temp")
+}
+
+// hwmonFile mirrors the lookup done by barista.run/modules/hwmon, returning
+// the *_input file matching the given chip name and sensor label.
+func hwmonFile(name, label string) string {
+	if name == "" && label == "" {
+		return ""
+	}
+	const baseDir = "/sys/class/hwmon"
+	chips, _ := afero.ReadDir(fs, baseDir)
+	for _, chip := range chips {
+		chipDir := filepath.Join(baseDir, chip.Name())
+		n, _ := afero.ReadFile(fs, filepath.Join(chipDir, "name"))
+		if strings.TrimSpace(string(n)) != name {
+			continue
+		}
+		entries, _ := afero.ReadDir(fs, chipDir)
+		for _, entry := range entries {
+			if !strings.HasSuffix(entry.Name(), "_label") {
+				continue
+			}
+			l, _ := afero.ReadFile(fs, filepath.Join(chipDir, entry.Name()))
+			if strings.TrimSpace(string(l)) == label {
+				input := strings.TrimSuffix(entry.Name(), "_label") + "_input"
+				return filepath.Join(chipDir, input)
+			}
+		}
+	}
+	return ""
+}
+
+// thermalZoneOfType mirrors barista.run/modules/cputemp.OfType, returning
+// the temp file of the first thermal zone of the given type.
+func thermalZoneOfType(typ string) string {
+	const baseDir = "/sys/class/thermal"
+	zones, _ := afero.ReadDir(fs, baseDir)
+	for _, zone := range zones {
+		typFile := filepath.Join(baseDir, zone.Name(), "type")
+		t, _ := afero.ReadFile(fs, typFile)
+		if strings.TrimSpace(string(t)) == typ {
+			return filepath.Join(baseDir, zone.Name(), "temp")
+		}
+	}
+	return ""
+}
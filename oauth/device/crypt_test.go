@@ -0,0 +1,35 @@
+package device
+
+import (
+	"testing"
+
+	"golang.org/x/oauth2"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func resetKey(key []byte) {
+	globalEncryptionKeyMu.Lock()
+	globalEncryptionKey = key
+	globalEncryptionKeyMu.Unlock()
+}
+
+func TestCryptRoundTrip(t *testing.T) {
+	resetKey([]byte("abcd"))
+	defer resetKey(nil)
+
+	fs = afero.NewMemMapFs()
+
+	tok := &oauth2.Token{AccessToken: "foobar", RefreshToken: "supersecret"}
+	require.NoError(t, storeToken("token.json", tok))
+
+	loaded, err := loadToken("token.json")
+	require.NoError(t, err)
+	require.Equal(t, tok.AccessToken, loaded.AccessToken)
+	require.Equal(t, tok.RefreshToken, loaded.RefreshToken)
+
+	resetKey([]byte("wrong key"))
+	_, err = loadToken("token.json")
+	require.Error(t, err, "with wrong key")
+}
@@ -0,0 +1,254 @@
+// Package device implements the OAuth 2.0 Device Authorization Grant
+// (RFC 8628) for providers that support it (e.g. GitHub Apps). It exists
+// alongside barista.run/oauth because that package only supports the
+// redirect/authorization-code flow, which requires a client secret and a
+// browser-reachable redirect URI -- awkward for a headless status bar.
+//
+// Tokens are persisted using the same AES-256/PBKDF2 scheme as
+// barista.run/oauth, encrypted with a key set once via SetEncryptionKey.
+package device // import "github.com/trbjo/barista_trbjo/oauth/device"
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Config represents a device-authorization-capable OAuth provider.
+type Config struct {
+	ClientID      string
+	Scopes        []string
+	DeviceAuthURL string
+	TokenURL      string
+
+	filename    string
+	mu          sync.Mutex
+	token       *oauth2.Token
+	tokenSource oauth2.TokenSource
+}
+
+func getConfigDir() string {
+	configRoot := os.ExpandEnv("$HOME/.config")
+	if xdgConfig, ok := os.LookupEnv("XDG_CONFIG_HOME"); ok {
+		configRoot = xdgConfig
+	}
+	return filepath.Join(configRoot, "barista", "oauth")
+}
+
+// Register creates a device-authorization Config for the given provider.
+// Tokens are stored alongside barista.run/oauth's own tokens, keyed by a
+// hash of the client ID and scopes so multiple modules can share a
+// provider's config directory without clashing.
+func Register(clientID, deviceAuthURL, tokenURL string, scopes ...string) *Config {
+	providerU, _ := url.Parse(deviceAuthURL)
+	hasher := sha256.New224()
+	io.WriteString(hasher, clientID)
+	for _, scope := range scopes {
+		io.WriteString(hasher, scope)
+	}
+	hash := base64.RawURLEncoding.EncodeToString(hasher.Sum(nil))
+	filename := filepath.Join(getConfigDir(),
+		fmt.Sprintf("%s_%s_device.json", providerU.Hostname(), hash))
+	return &Config{
+		ClientID:      clientID,
+		Scopes:        scopes,
+		DeviceAuthURL: deviceAuthURL,
+		TokenURL:      tokenURL,
+		filename:      filename,
+	}
+}
+
+// Prompt carries the user-facing portion of the device flow: the code the
+// user must enter, and where to enter it.
+type Prompt struct {
+	UserCode        string
+	VerificationURI string
+}
+
+type deviceCodeResponse struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	Error        string `json:"error"`
+}
+
+// Authenticate runs the RFC 8628 device flow to completion: it requests a
+// device code, invokes onPrompt with the code the user needs to enter, and
+// polls the token endpoint until the user approves, denies, or the code
+// expires. The resulting token is persisted for future calls to Token/Client.
+func (c *Config) Authenticate(onPrompt func(Prompt)) (*oauth2.Token, error) {
+	dc, err := c.requestDeviceCode()
+	if err != nil {
+		return nil, err
+	}
+	onPrompt(Prompt{UserCode: dc.UserCode, VerificationURI: dc.VerificationURI})
+
+	interval := time.Duration(dc.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(dc.ExpiresIn) * time.Second)
+	for {
+		sleep(interval)
+		if time.Now().After(deadline) {
+			return nil, errors.New("device code expired before authorization")
+		}
+		tok, err := c.pollToken(dc.DeviceCode)
+		switch {
+		case err == errAuthorizationPending:
+			continue
+		case err == errSlowDown:
+			interval += 5 * time.Second
+			continue
+		case err != nil:
+			return nil, err
+		}
+		c.mu.Lock()
+		c.token = tok
+		c.tokenSource = nil
+		c.mu.Unlock()
+		return tok, c.store(tok)
+	}
+}
+
+var (
+	errAuthorizationPending = errors.New("authorization_pending")
+	errSlowDown             = errors.New("slow_down")
+)
+
+var sleep = time.Sleep // for tests.
+
+func (c *Config) requestDeviceCode() (*deviceCodeResponse, error) {
+	form := url.Values{
+		"client_id": {c.ClientID},
+		"scope":     {strings.Join(c.Scopes, " ")},
+	}
+	var dc deviceCodeResponse
+	if err := postForm(c.DeviceAuthURL, form, &dc); err != nil {
+		return nil, err
+	}
+	return &dc, nil
+}
+
+func (c *Config) pollToken(deviceCode string) (*oauth2.Token, error) {
+	form := url.Values{
+		"client_id":   {c.ClientID},
+		"device_code": {deviceCode},
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+	}
+	var tr tokenResponse
+	if err := postForm(c.TokenURL, form, &tr); err != nil {
+		return nil, err
+	}
+	switch tr.Error {
+	case "":
+		// success
+	case "authorization_pending":
+		return nil, errAuthorizationPending
+	case "slow_down":
+		return nil, errSlowDown
+	case "expired_token":
+		return nil, errors.New("device code expired")
+	case "access_denied":
+		return nil, errors.New("authorization denied by user")
+	default:
+		return nil, fmt.Errorf("device token error: %s", tr.Error)
+	}
+	tok := &oauth2.Token{
+		AccessToken:  tr.AccessToken,
+		TokenType:    tr.TokenType,
+		RefreshToken: tr.RefreshToken,
+	}
+	if tr.ExpiresIn > 0 {
+		tok.Expiry = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+	return tok, nil
+}
+
+func postForm(endpoint string, form url.Values, out interface{}) error {
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP Status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *Config) oauth2Config() *oauth2.Config {
+	return &oauth2.Config{
+		ClientID: c.ClientID,
+		Endpoint: oauth2.Endpoint{TokenURL: c.TokenURL},
+		Scopes:   c.Scopes,
+	}
+}
+
+// Token returns a valid access token, loading and refreshing the persisted
+// token as needed. It does not perform the initial device authorization;
+// use Authenticate for that.
+func (c *Config) Token() (*oauth2.Token, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tokenSource == nil {
+		tok, err := loadToken(c.filename)
+		if err != nil {
+			return nil, err
+		}
+		c.token = tok
+		c.tokenSource = c.oauth2Config().TokenSource(context.Background(), tok)
+	}
+	if c.token.Valid() {
+		return c.token, nil
+	}
+	tok, err := c.tokenSource.Token()
+	if err != nil {
+		return nil, err
+	}
+	c.token = tok
+	return tok, c.store(tok)
+}
+
+// Client returns an http client that authorizes requests using the
+// previously persisted token for this configuration, refreshing it as
+// needed.
+func (c *Config) Client() (*http.Client, error) {
+	if _, err := c.Token(); err != nil {
+		return nil, err
+	}
+	return oauth2.NewClient(context.Background(), c), nil
+}
+
+func (c *Config) store(tok *oauth2.Token) error {
+	os.MkdirAll(filepath.Dir(c.filename), 0700)
+	return storeToken(c.filename, tok)
+}
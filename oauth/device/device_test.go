@@ -0,0 +1,130 @@
+package device
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request)) (*Config, func()) {
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	cfg := &Config{
+		ClientID:      "test-client",
+		Scopes:        []string{"notifications"},
+		DeviceAuthURL: server.URL + "/device",
+		TokenURL:      server.URL + "/token",
+	}
+	return cfg, server.Close
+}
+
+func TestPollTokenTransitions(t *testing.T) {
+	cases := []struct {
+		name      string
+		respond   tokenResponse
+		wantErr   error
+		wantPlain bool // true if the error isn't one of the sentinel errors
+	}{
+		{name: "authorization_pending", respond: tokenResponse{Error: "authorization_pending"}, wantErr: errAuthorizationPending},
+		{name: "slow_down", respond: tokenResponse{Error: "slow_down"}, wantErr: errSlowDown},
+		{name: "expired_token", respond: tokenResponse{Error: "expired_token"}, wantPlain: true},
+		{name: "access_denied", respond: tokenResponse{Error: "access_denied"}, wantPlain: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg, closeServer := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(c.respond)
+			})
+			defer closeServer()
+
+			_, err := cfg.pollToken("device-code")
+			require.Error(t, err)
+			if c.wantErr != nil {
+				require.Equal(t, c.wantErr, err)
+			}
+			if c.wantPlain {
+				require.NotEqual(t, errAuthorizationPending, err)
+				require.NotEqual(t, errSlowDown, err)
+			}
+		})
+	}
+
+	t.Run("success", func(t *testing.T) {
+		cfg, closeServer := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(tokenResponse{
+				AccessToken: "the-token",
+				TokenType:   "bearer",
+				ExpiresIn:   3600,
+			})
+		})
+		defer closeServer()
+
+		tok, err := cfg.pollToken("device-code")
+		require.NoError(t, err)
+		require.Equal(t, "the-token", tok.AccessToken)
+		require.False(t, tok.Expiry.IsZero())
+	})
+}
+
+func TestAuthenticatePollsUntilApproved(t *testing.T) {
+	sleep = func(time.Duration) {}
+	defer func() { sleep = time.Sleep }()
+
+	resetKey([]byte("abcd"))
+	defer resetKey(nil)
+	fs = afero.NewMemMapFs()
+
+	var mu sync.Mutex
+	pollCount := 0
+	cfg, closeServer := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/device":
+			json.NewEncoder(w).Encode(deviceCodeResponse{
+				DeviceCode:      "device-code",
+				UserCode:        "ABCD-1234",
+				VerificationURI: "https://example.com/activate",
+				ExpiresIn:       900,
+				Interval:        0,
+			})
+		case "/token":
+			mu.Lock()
+			pollCount++
+			n := pollCount
+			mu.Unlock()
+			switch {
+			case n < 2:
+				json.NewEncoder(w).Encode(tokenResponse{Error: "authorization_pending"})
+			case n == 2:
+				json.NewEncoder(w).Encode(tokenResponse{Error: "slow_down"})
+			default:
+				json.NewEncoder(w).Encode(tokenResponse{AccessToken: "the-token", TokenType: "bearer"})
+			}
+		}
+	})
+	defer closeServer()
+
+	var gotPrompt Prompt
+	tok, err := cfg.Authenticate(func(p Prompt) { gotPrompt = p })
+	require.NoError(t, err)
+	require.Equal(t, "the-token", tok.AccessToken)
+	require.Equal(t, "ABCD-1234", gotPrompt.UserCode)
+}
+
+func TestPostFormRejectsNonOKStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, "<html>rate limited</html>")
+	}))
+	defer server.Close()
+
+	var out tokenResponse
+	err := postForm(server.URL, nil, &out)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "503")
+}